@@ -0,0 +1,105 @@
+// Package packed implements packed-vector encoding on top of the paillier
+// package, so a vote tally can add up k per-candidate counters with a
+// single homomorphic ciphertext operation instead of k separate ones.
+package packed
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+
+	"github.com/Microsoft-tele/PaillierVoteSystemRemoteRouter/paillier"
+)
+
+// ErrSlotOverflow is returned when a vector value is too large to fit in a
+// slot of the chosen width B.
+var ErrSlotOverflow = errors.New("packed: value does not fit in slot width B")
+
+// EncryptVector encodes values as a single plaintext m = Σ values[i]*B^i
+// and encrypts it under pub. It returns ErrSlotOverflow if any values[i] is
+// negative or not less than B.
+func EncryptVector(pub *paillier.PublicKey, values []int64, B int64) ([]byte, error) {
+	m, err := encodeVector(values, B)
+	if err != nil {
+		return nil, err
+	}
+	return paillier.Encrypt(pub, m.Bytes())
+}
+
+// AddEncryptedVectors homomorphically adds two packed ciphertexts,
+// summing every slot in parallel.
+func AddEncryptedVectors(pub *paillier.PublicKey, c1, c2 []byte) []byte {
+	return paillier.AddCipher(pub, c1, c2)
+}
+
+// AddScalarVector homomorphically adds the plaintext vector values to the
+// packed ciphertext c, summing every slot in parallel.
+func AddScalarVector(pub *paillier.PublicKey, c []byte, values []int64, B int64) ([]byte, error) {
+	m, err := encodeVector(values, B)
+	if err != nil {
+		return nil, err
+	}
+	return paillier.Add(pub, c, m.Bytes()), nil
+}
+
+// DecryptVector decrypts c and unpacks it into k slot values, each
+// recovered by repeatedly taking the plaintext mod B and dividing by B.
+func DecryptVector(priv *paillier.PrivateKey, c []byte, k int, B int64) ([]int64, error) {
+	plainBytes, err := paillier.Decrypt(priv, c)
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(big.Int).SetBytes(plainBytes)
+	base := big.NewInt(B)
+
+	values := make([]int64, k)
+	rem := new(big.Int)
+	for i := 0; i < k; i++ {
+		m.DivMod(m, base, rem)
+		values[i] = rem.Int64()
+	}
+	return values, nil
+}
+
+// encodeVector packs values into a single big.Int m = Σ values[i]*B^i,
+// rejecting any value that does not fit in [0, B), since such a value
+// would bleed into its neighboring slot.
+func encodeVector(values []int64, B int64) (*big.Int, error) {
+	m := new(big.Int)
+	weight := big.NewInt(1)
+	base := big.NewInt(B)
+
+	for _, v := range values {
+		if v < 0 || v >= B {
+			return nil, ErrSlotOverflow
+		}
+		m.Add(m, new(big.Int).Mul(big.NewInt(v), weight))
+		weight.Mul(weight, base)
+	}
+	return m, nil
+}
+
+// ChooseSlotWidth picks a slot width B wide enough to hold the running
+// total of up to maxVoters additions of at most maxPerSlot each, without
+// one slot's sum ever overflowing into the next. It returns ok=false if
+// packing k such slots would not fit under a Paillier modulus of pubBits
+// bits.
+func ChooseSlotWidth(maxPerSlot, maxVoters, k, pubBits int) (B int, ok bool) {
+	if maxPerSlot <= 0 || maxVoters <= 0 || k <= 0 || pubBits <= 0 {
+		return 0, false
+	}
+
+	width := maxPerSlot * maxVoters
+	if width <= 0 || width/maxVoters != maxPerSlot { // overflowed int
+		return 0, false
+	}
+	width++ // B must exceed the maximum attainable slot total
+
+	neededBits := bits.Len(uint(width)) * k
+	if neededBits >= pubBits {
+		return 0, false
+	}
+
+	return width, true
+}
@@ -0,0 +1,95 @@
+package packed
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/Microsoft-tele/PaillierVoteSystemRemoteRouter/paillier"
+)
+
+func TestEncryptDecryptVectorRoundTrip(t *testing.T) {
+	priv, err := paillier.GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	const B = int64(1000)
+	values := []int64{3, 0, 999, 42, 7}
+
+	cipherText, err := EncryptVector(&priv.PublicKey, values, B)
+	if err != nil {
+		t.Fatalf("EncryptVector: %v", err)
+	}
+
+	decoded, err := DecryptVector(priv, cipherText, len(values), B)
+	if err != nil {
+		t.Fatalf("DecryptVector: %v", err)
+	}
+
+	for i, want := range values {
+		if decoded[i] != want {
+			t.Fatalf("slot %d = %d, want %d", i, decoded[i], want)
+		}
+	}
+}
+
+func TestAddEncryptedAndScalarVectors(t *testing.T) {
+	priv, err := paillier.GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	const B = int64(1000)
+	a := []int64{1, 2, 3}
+	b := []int64{10, 20, 30}
+
+	ca, err := EncryptVector(&priv.PublicKey, a, B)
+	if err != nil {
+		t.Fatalf("EncryptVector(a): %v", err)
+	}
+	cb, err := EncryptVector(&priv.PublicKey, b, B)
+	if err != nil {
+		t.Fatalf("EncryptVector(b): %v", err)
+	}
+
+	summed := AddEncryptedVectors(&priv.PublicKey, ca, cb)
+	decoded, err := DecryptVector(priv, summed, len(a), B)
+	if err != nil {
+		t.Fatalf("DecryptVector: %v", err)
+	}
+	want := []int64{11, 22, 33}
+	for i := range want {
+		if decoded[i] != want[i] {
+			t.Fatalf("AddEncryptedVectors slot %d = %d, want %d", i, decoded[i], want[i])
+		}
+	}
+
+	withScalar, err := AddScalarVector(&priv.PublicKey, ca, []int64{1, 1, 1}, B)
+	if err != nil {
+		t.Fatalf("AddScalarVector: %v", err)
+	}
+	decoded, err = DecryptVector(priv, withScalar, len(a), B)
+	if err != nil {
+		t.Fatalf("DecryptVector: %v", err)
+	}
+	want = []int64{2, 3, 4}
+	for i := range want {
+		if decoded[i] != want[i] {
+			t.Fatalf("AddScalarVector slot %d = %d, want %d", i, decoded[i], want[i])
+		}
+	}
+}
+
+func TestChooseSlotWidth(t *testing.T) {
+	B, ok := ChooseSlotWidth(1, 1000, 5, 256)
+	if !ok {
+		t.Fatal("ChooseSlotWidth rejected parameters that should fit under a 256-bit modulus")
+	}
+	if B <= 1000 {
+		t.Fatalf("ChooseSlotWidth = %d, want > maxPerSlot*maxVoters (1000)", B)
+	}
+
+	if _, ok := ChooseSlotWidth(1000000, 1000000, 100, 256); ok {
+		t.Fatal("ChooseSlotWidth accepted parameters that cannot fit under a 256-bit modulus")
+	}
+}
@@ -8,6 +8,7 @@ import (
 )
 
 var one = big.NewInt(1)
+var two = big.NewInt(2)
 
 // ErrMessageTooLong is returned when attempting to encrypt a message which is
 // too large for the size of the public key.
@@ -45,6 +46,7 @@ func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
 			N1:       n,
 			NSquared: new(big.Int).Mul(n, n),
 			G:        new(big.Int).Add(n, one), // g = N1 + 1
+			S:        1,
 		},
 		P:         p,
 		PP:        pp,
@@ -60,6 +62,82 @@ func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
 
 }
 
+// GenerateSafeKey generates a Paillier keypair like GenerateKey, but
+// requires P and Q to be strong (safe) primes: P=2p'+1 and Q=2q'+1 with p'
+// and q' themselves prime. It also verifies gcd(N, phi(N))=1 and rejects
+// candidates where p'=q' or where P and Q differ in bit length, guarding
+// against a degenerate N. Safe primes are
+// required for the soundness of the threshold and zero-knowledge proof
+// extensions built on top of this package; GenerateKey does not provide
+// this guarantee and remains the faster, general-purpose path.
+func GenerateSafeKey(random io.Reader, bits int) (*PrivateKey, error) {
+	for {
+		p, pPrime, err := SafePrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, qPrime, err := SafePrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+
+		if pPrime.Cmp(qPrime) == 0 {
+			continue
+		}
+		if p.BitLen() != q.BitLen() {
+			continue
+		}
+
+		n := new(big.Int).Mul(p, q)
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		if new(big.Int).GCD(nil, nil, n, phi).Cmp(one) != 0 {
+			continue
+		}
+
+		pp := new(big.Int).Mul(p, p)
+		qq := new(big.Int).Mul(q, q)
+
+		return &PrivateKey{
+			PublicKey: PublicKey{
+				N1:       n,
+				NSquared: new(big.Int).Mul(n, n),
+				G:        new(big.Int).Add(n, one),
+				S:        1,
+			},
+			P:         p,
+			PP:        pp,
+			Pminusone: new(big.Int).Sub(p, one),
+			Q:         q,
+			QQ:        qq,
+			Qminusone: new(big.Int).Sub(q, one),
+			Pinvq:     new(big.Int).ModInverse(p, q),
+			Hp:        h(p, pp, n),
+			Hq:        h(q, qq, n),
+			N:         n,
+			Pprime:    pPrime,
+			Qprime:    qPrime,
+		}, nil
+	}
+}
+
+// SafePrime returns a prime p = 2p'+1 where p' is also prime, both of
+// approximately bits length. It is exported so other packages building
+// threshold or zero-knowledge extensions on top of this one (which require
+// safe primes for soundness) can generate them the same way GenerateSafeKey
+// does, rather than carrying their own copy that can drift out of sync.
+func SafePrime(random io.Reader, bits int) (p, pPrime *big.Int, err error) {
+	for {
+		pPrime, err = rand.Prime(random, bits-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		p = new(big.Int).Add(new(big.Int).Mul(two, pPrime), one)
+		if p.ProbablyPrime(20) {
+			return p, pPrime, nil
+		}
+	}
+}
+
 // PrivateKey represents a Paillier key.
 type PrivateKey struct {
 	PublicKey
@@ -73,6 +151,24 @@ type PrivateKey struct {
 	Hp        *big.Int
 	Hq        *big.Int
 	N         *big.Int
+
+	// Pprime and Qprime are p' and q' when P=2p'+1 and Q=2q'+1 are strong
+	// (safe) primes, as produced by GenerateSafeKey. They are nil for keys
+	// produced by GenerateKey, which places no constraint on P and Q beyond
+	// primality.
+	Pprime *big.Int
+	Qprime *big.Int
+}
+
+// Lambda returns Carmichael's function λ(N) = 2p'q' for a key generated by
+// GenerateSafeKey. It returns an error if the key was not generated with
+// safe primes, since λ(N) cannot be derived from P and Q alone in that case
+// without refactoring P-1 and Q-1.
+func (priv *PrivateKey) Lambda() (*big.Int, error) {
+	if priv.Pprime == nil || priv.Qprime == nil {
+		return nil, errors.New("paillier: key was not generated with safe primes, Carmichael's function is unavailable")
+	}
+	return new(big.Int).Mul(two, new(big.Int).Mul(priv.Pprime, priv.Qprime)), nil
 }
 
 // PublicKey represents the public part of a Paillier key.
@@ -80,6 +176,31 @@ type PublicKey struct {
 	N1       *big.Int // modulus
 	G        *big.Int // N1+1, since P and Q are same length
 	NSquared *big.Int
+
+	// S is the Damgård–Jurik exponent: plaintexts live in Z_{N1^S} and
+	// ciphertexts in Z_{N1^(S+1)}*. S<=0 is treated as S=1, the classical
+	// Paillier scheme that NSquared (N1^2) already describes, so existing
+	// callers that never set S keep working unchanged.
+	S int
+}
+
+// sExp returns the effective Damgård–Jurik exponent, treating an unset
+// (zero-value) S as the classical Paillier case S=1.
+func (pub *PublicKey) sExp() int {
+	if pub.S <= 0 {
+		return 1
+	}
+	return pub.S
+}
+
+// modulus returns N1^(S+1), the ciphertext modulus for the effective S.
+// For S=1 this is exactly NSquared.
+func (pub *PublicKey) modulus() *big.Int {
+	s := pub.sExp()
+	if s == 1 {
+		return pub.NSquared
+	}
+	return new(big.Int).Exp(pub.N1, big.NewInt(int64(s+1)), nil)
 }
 
 func h(p *big.Int, pp *big.Int, n *big.Int) *big.Int {
@@ -118,34 +239,179 @@ func EncryptAndNonce(pubKey *PublicKey, plainText []byte) ([]byte, *big.Int, err
 }
 
 // EncryptWithNonce encrypts a plain text represented as a byte array using the
-// provided nonce to perform encryption. The passed plain text MUST NOT be
-// larger than the modulus of the passed public key.
+// provided nonce to perform encryption. The passed plain text MUST be less
+// than N1^S (N1 itself, for the classical S=1 case).
+//
+// For S=1 this reduces to the familiar c = g^m * r^N1 mod N1^2. For S>1 it
+// implements Damgård–Jurik encryption, c = (1+N1)^m * r^(N1^S) mod N1^(S+1),
+// where (1+N1)^m is computed via its binomial expansion
+// Σ_{k=0}^{S} C(m,k)*N1^k mod N1^(S+1) rather than a naive modexp with an
+// exponent as large as N1^S.
 func EncryptWithNonce(pubKey *PublicKey, r *big.Int, plainText []byte) (*big.Int, error) {
 	m := new(big.Int).SetBytes(plainText)
-	if pubKey.N1.Cmp(m) < 1 { // N1 < m
+	s := pubKey.sExp()
+	mod := pubKey.modulus()
+
+	maxPlain := new(big.Int).Exp(pubKey.N1, big.NewInt(int64(s)), nil)
+	if maxPlain.Cmp(m) < 1 { // N1^S <= m
 		return nil, ErrMessageTooLong
 	}
 
-	// c = g^m * r^N1 mod N1^2 = ((m*N1+1) mod N1^2) * r^N1 mod N1^2
-	n := pubKey.N1
+	gToM, nToS := binomialPow(m, pubKey.N1, mod, s)
 	c := new(big.Int).Mod(
-		new(big.Int).Mul(
-			new(big.Int).Mod(new(big.Int).Add(one, new(big.Int).Mul(m, n)), pubKey.NSquared),
-			new(big.Int).Exp(r, n, pubKey.NSquared),
-		),
-		pubKey.NSquared,
+		new(big.Int).Mul(gToM, new(big.Int).Exp(r, nToS, mod)),
+		mod,
 	)
 
 	return c, nil
 }
 
+// binomialPow computes (1+n)^m mod mod = Σ_{k=0}^{s} C(m,k)*n^k mod mod,
+// along with n^s, without ever raising anything to the (potentially huge)
+// exponent m directly. Each term is derived from the previous one via
+// C(m,k) = C(m,k-1)*(m-k+1)/k, computed mod mod using k's modular inverse
+// (k<=s is always coprime to mod=N1^(s+1) for any s small enough to be
+// practical).
+func binomialPow(m, n, mod *big.Int, s int) (*big.Int, *big.Int) {
+	sum := new(big.Int).Set(one)
+	term := new(big.Int).Set(one) // C(m,0)
+	nToK := new(big.Int).Set(one) // n^0
+
+	for k := 1; k <= s; k++ {
+		factor := new(big.Int).Sub(m, big.NewInt(int64(k-1)))
+		term.Mul(term, factor)
+		kInv := new(big.Int).ModInverse(big.NewInt(int64(k)), mod)
+		term.Mul(term, kInv)
+		term.Mod(term, mod)
+
+		nToK = new(big.Int).Mul(nToK, n)
+		sum.Add(sum, new(big.Int).Mul(term, nToK))
+		sum.Mod(sum, mod)
+	}
+
+	return sum, nToK
+}
+
+// invertBinomialPow recovers e from v = (1+n)^e mod n^(s+1), given 0<=e<n^s.
+// It reconstructs e one base-n digit at a time: having recovered the low j
+// digits as e_j, it divides v by (1+n)^(e_j) to cancel out their
+// contribution, leaving (1+n)^(e-e_j) mod n^(j+2), whose only surviving term
+// (since n^j | e-e_j) isolates the next digit.
+func invertBinomialPow(v, n *big.Int, s int) *big.Int {
+	e := big.NewInt(0)
+	nToJ := big.NewInt(1) // n^j
+
+	for j := 0; j < s; j++ {
+		modJ := new(big.Int).Exp(n, big.NewInt(int64(j+2)), nil) // n^(j+2)
+
+		base := new(big.Int).Add(n, one)
+		baseToE := new(big.Int).Exp(base, e, modJ)
+		baseToEInv := new(big.Int).ModInverse(baseToE, modJ)
+
+		w := new(big.Int).Mod(new(big.Int).Mul(new(big.Int).Mod(v, modJ), baseToEInv), modJ)
+
+		nToJPlus1 := new(big.Int).Mul(nToJ, n)
+		digit := new(big.Int).Div(new(big.Int).Sub(w, one), nToJPlus1)
+		digit.Mod(digit, n)
+
+		e.Add(e, new(big.Int).Mul(digit, nToJ))
+		nToJ = nToJPlus1
+	}
+
+	return e
+}
+
 // Decrypt decrypts the passed cipher text.
+//
+// To avoid leaking timing information correlated with the attacker-chosen
+// cipherText (dangerous in a vote-tally server that decrypts ciphertexts it
+// did not generate), the plaintext is additively blinded before the CRT
+// exponentiations: a random s is encrypted and homomorphically added to the
+// cipher text, the sum (m+s mod N1) is recovered, and s is subtracted back
+// out. The CRT work below therefore always operates on an unpredictable
+// value rather than one under the caller's control.
 func Decrypt(privKey *PrivateKey, cipherText []byte) ([]byte, error) {
+	mod := privKey.modulus()
 	c := new(big.Int).SetBytes(cipherText)
-	if privKey.NSquared.Cmp(c) < 1 { // c < N1^2
+	if mod.Cmp(c) < 1 {
 		return nil, ErrMessageTooLong
 	}
 
+	if privKey.sExp() > 1 {
+		return decryptGeneral(privKey, c)
+	}
+
+	privKey.Precompute()
+
+	s, err := rand.Int(rand.Reader, privKey.N1)
+	if err != nil {
+		return nil, err
+	}
+	blindCipher, _, err := EncryptAndNonce(&privKey.PublicKey, s.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	blinded := new(big.Int).Mod(
+		new(big.Int).Mul(c, new(big.Int).SetBytes(blindCipher)),
+		privKey.NSquared,
+	)
+
+	mBlinded := decryptRaw(privKey, blinded)
+	m := new(big.Int).Mod(new(big.Int).Sub(mBlinded, s), privKey.N1)
+
+	return m.Bytes(), nil
+}
+
+// decryptGeneral decrypts a ciphertext encrypted under S>1 (Damgård–Jurik
+// packing). It requires privKey.Lambda(), since the CRT-with-Hp/Hq shortcut
+// used for the classical S=1 case does not generalize; the recursive
+// lifting below works directly with λ(N) instead.
+//
+// As in the S=1 path, the cipher text is additively blinded before the
+// lambda exponentiation: this is the code path a vote-tally server runs on
+// attacker-chosen ciphertexts (packed per-candidate counters), so it needs
+// the same protection against timing side channels that Decrypt applies
+// for S=1.
+func decryptGeneral(privKey *PrivateKey, c *big.Int) ([]byte, error) {
+	lambda, err := privKey.Lambda()
+	if err != nil {
+		return nil, err
+	}
+
+	s := privKey.sExp()
+	mod := privKey.modulus()
+	n := privKey.N1
+	nToS := new(big.Int).Exp(n, big.NewInt(int64(s)), nil)
+
+	blind, err := rand.Int(rand.Reader, nToS)
+	if err != nil {
+		return nil, err
+	}
+	blindCipher, err := Encrypt(&privKey.PublicKey, blind.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	blinded := new(big.Int).Mod(
+		new(big.Int).Mul(c, new(big.Int).SetBytes(blindCipher)),
+		mod,
+	)
+
+	cLambda := new(big.Int).Exp(blinded, lambda, mod)
+	e := invertBinomialPow(cLambda, n, s)
+
+	lambdaInv := new(big.Int).ModInverse(new(big.Int).Mod(lambda, nToS), nToS)
+	if lambdaInv == nil {
+		return nil, errors.New("paillier: lambda not invertible mod N1^S")
+	}
+
+	mBlinded := new(big.Int).Mod(new(big.Int).Mul(e, lambdaInv), nToS)
+	m := new(big.Int).Mod(new(big.Int).Sub(mBlinded, blind), nToS)
+	return m.Bytes(), nil
+}
+
+// decryptRaw runs the CRT decryption procedure on an already-reduced
+// cipher text, with no blinding of its own.
+func decryptRaw(privKey *PrivateKey, c *big.Int) *big.Int {
 	cp := new(big.Int).Exp(c, privKey.Pminusone, privKey.PP)
 	lp := l(cp, privKey.P)
 	mp := new(big.Int).Mod(new(big.Int).Mul(lp, privKey.Hp), privKey.P)
@@ -154,9 +420,49 @@ func Decrypt(privKey *PrivateKey, cipherText []byte) ([]byte, error) {
 
 	mqq := new(big.Int).Mul(lq, privKey.Hq)
 	mq := new(big.Int).Mod(mqq, privKey.Q)
-	m := crt(mp, mq, privKey)
+	return crt(mp, mq, privKey)
+}
 
-	return m.Bytes(), nil
+// Precompute fills in NSquared, PP, QQ, Pminusone, Qminusone, Pinvq, Hp and
+// Hq from P and Q if they are not already set. GenerateKey and
+// GenerateSafeKey already populate these, so Precompute is a no-op for keys
+// they return; it exists for PrivateKeys assembled by hand, e.g. after
+// deserializing only P and Q, so callers don't have to duplicate the CRT
+// setup themselves before calling Decrypt.
+func (priv *PrivateKey) Precompute() {
+	if priv.N == nil {
+		priv.N = new(big.Int).Mul(priv.P, priv.Q)
+	}
+	if priv.N1 == nil {
+		priv.N1 = priv.N
+	}
+	if priv.NSquared == nil {
+		priv.NSquared = new(big.Int).Mul(priv.N1, priv.N1)
+	}
+	if priv.G == nil {
+		priv.G = new(big.Int).Add(priv.N1, one)
+	}
+	if priv.PP == nil {
+		priv.PP = new(big.Int).Mul(priv.P, priv.P)
+	}
+	if priv.QQ == nil {
+		priv.QQ = new(big.Int).Mul(priv.Q, priv.Q)
+	}
+	if priv.Pminusone == nil {
+		priv.Pminusone = new(big.Int).Sub(priv.P, one)
+	}
+	if priv.Qminusone == nil {
+		priv.Qminusone = new(big.Int).Sub(priv.Q, one)
+	}
+	if priv.Pinvq == nil {
+		priv.Pinvq = new(big.Int).ModInverse(priv.P, priv.Q)
+	}
+	if priv.Hp == nil {
+		priv.Hp = h(priv.P, priv.PP, priv.N)
+	}
+	if priv.Hq == nil {
+		priv.Hq = h(priv.Q, priv.QQ, priv.N)
+	}
 }
 
 func crt(mp *big.Int, mq *big.Int, privKey *PrivateKey) *big.Int {
@@ -172,10 +478,10 @@ func AddCipher(pubKey *PublicKey, cipher1, cipher2 []byte) []byte {
 	x := new(big.Int).SetBytes(cipher1)
 	y := new(big.Int).SetBytes(cipher2)
 
-	// x * y mod N1^2
+	// x * y mod N1^(S+1)
 	return new(big.Int).Mod(
 		new(big.Int).Mul(x, y),
-		pubKey.NSquared,
+		pubKey.modulus(),
 	).Bytes()
 }
 
@@ -186,11 +492,12 @@ func AddCipher(pubKey *PublicKey, cipher1, cipher2 []byte) []byte {
 func Add(pubKey *PublicKey, cipher, constant []byte) []byte {
 	c := new(big.Int).SetBytes(cipher)
 	x := new(big.Int).SetBytes(constant)
+	mod := pubKey.modulus()
 
-	// c * g ^ x mod N1^2
+	// c * g ^ x mod N1^(S+1)
 	return new(big.Int).Mod(
-		new(big.Int).Mul(c, new(big.Int).Exp(pubKey.G, x, pubKey.NSquared)),
-		pubKey.NSquared,
+		new(big.Int).Mul(c, new(big.Int).Exp(pubKey.G, x, mod)),
+		mod,
 	).Bytes()
 }
 
@@ -202,6 +509,6 @@ func Mul(pubKey *PublicKey, cipher []byte, constant []byte) []byte {
 	c := new(big.Int).SetBytes(cipher)
 	x := new(big.Int).SetBytes(constant)
 
-	// c ^ x mod N1^2
-	return new(big.Int).Exp(c, x, pubKey.NSquared).Bytes()
+	// c ^ x mod N1^(S+1)
+	return new(big.Int).Exp(c, x, pubKey.modulus()).Bytes()
 }
@@ -0,0 +1,152 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	for _, want := range []int64{0, 1, 42, 12345} {
+		cipherText, nonce, err := EncryptAndNonce(&priv.PublicKey, big.NewInt(want).Bytes())
+		if err != nil {
+			t.Fatalf("EncryptAndNonce(%d): %v", want, err)
+		}
+		if nonce.Sign() < 0 || nonce.Cmp(priv.N1) >= 0 {
+			t.Fatalf("nonce %s out of range [0, N1)", nonce)
+		}
+
+		decrypted, err := Decrypt(priv, cipherText)
+		if err != nil {
+			t.Fatalf("Decrypt(%d): %v", want, err)
+		}
+		got := new(big.Int).SetBytes(decrypted)
+		if got.Int64() != want {
+			t.Fatalf("Decrypt(Encrypt(%d)) = %s, want %d", want, got, want)
+		}
+	}
+}
+
+func TestDecryptBlindingIsNondeterministicButCorrect(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cipherText, err := Encrypt(&priv.PublicKey, big.NewInt(99).Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		decrypted, err := Decrypt(priv, cipherText)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if got := new(big.Int).SetBytes(decrypted).Int64(); got != 99 {
+			t.Fatalf("Decrypt run %d = %d, want 99 (blinding must not change the plaintext)", i, got)
+		}
+	}
+}
+
+func TestGenerateSafeKeyLambda(t *testing.T) {
+	priv, err := GenerateSafeKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateSafeKey: %v", err)
+	}
+
+	lambda, err := priv.Lambda()
+	if err != nil {
+		t.Fatalf("Lambda: %v", err)
+	}
+	if lambda.Sign() <= 0 {
+		t.Fatalf("Lambda() = %s, want a positive value", lambda)
+	}
+
+	plainText := []byte{7}
+	cipherText, err := Encrypt(&priv.PublicKey, plainText)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	decrypted, err := Decrypt(priv, cipherText)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if len(decrypted) != 1 || decrypted[0] != 7 {
+		t.Fatalf("Decrypt = %v, want [7]", decrypted)
+	}
+}
+
+func TestDamgardJurikRoundTrip(t *testing.T) {
+	priv, err := GenerateSafeKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateSafeKey: %v", err)
+	}
+	priv.S = 2
+	maxPlain := new(big.Int).Exp(priv.N1, big.NewInt(2), nil)
+
+	want := new(big.Int).Sub(maxPlain, big.NewInt(1000)) // a value that needs S=2 to fit
+	cipherText, err := Encrypt(&priv.PublicKey, want.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	decrypted, err := Decrypt(priv, cipherText)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if got := new(big.Int).SetBytes(decrypted); got.Cmp(want) != 0 {
+		t.Fatalf("Decrypt(Encrypt(%s)) = %s, want %s", want, got, want)
+	}
+
+	// N1^1 < want <= N1^2, so the classical S=1 plaintext bound must reject it.
+	if _, err := EncryptWithNonce(&PublicKey{N1: priv.N1, G: priv.G, NSquared: priv.NSquared, S: 1}, big.NewInt(1), want.Bytes()); err == nil {
+		t.Fatal("S=1 EncryptWithNonce accepted a plaintext larger than N1")
+	}
+}
+
+func TestDamgardJurikHomomorphicAdd(t *testing.T) {
+	priv, err := GenerateSafeKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateSafeKey: %v", err)
+	}
+	priv.S = 2
+
+	a := big.NewInt(123456789)
+	b := big.NewInt(987654321)
+
+	ca, err := Encrypt(&priv.PublicKey, a.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt(a): %v", err)
+	}
+	cb, err := Encrypt(&priv.PublicKey, b.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt(b): %v", err)
+	}
+
+	sum := AddCipher(&priv.PublicKey, ca, cb)
+	decrypted, err := Decrypt(priv, sum)
+	if err != nil {
+		t.Fatalf("Decrypt(sum): %v", err)
+	}
+
+	want := new(big.Int).Add(a, b)
+	if got := new(big.Int).SetBytes(decrypted); got.Cmp(want) != 0 {
+		t.Fatalf("Decrypt(AddCipher(Enc(a),Enc(b))) = %s, want %s", got, want)
+	}
+}
+
+func TestGenerateKeyHasNoLambda(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if _, err := priv.Lambda(); err == nil {
+		t.Fatal("Lambda() on a GenerateKey key should fail, it wasn't generated with safe primes")
+	}
+}
@@ -0,0 +1,404 @@
+// Package threshold implements Damgård–Jurik-style threshold Paillier key
+// generation and decryption on top of the paillier package's PublicKey type.
+// It lets a tally authority split a Paillier decryption key across n
+// trustees such that any t of them can jointly decrypt a ciphertext while
+// no coalition smaller than t learns anything about the plaintext.
+package threshold
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/Microsoft-tele/PaillierVoteSystemRemoteRouter/paillier"
+)
+
+var (
+	one = big.NewInt(1)
+	two = big.NewInt(2)
+)
+
+// ErrNotEnoughShares is returned when CombineShares is called with fewer
+// decryption shares than the threshold requires.
+var ErrNotEnoughShares = errors.New("threshold: not enough decryption shares")
+
+// ErrInvalidShareProof is returned by VerifyShare when a trustee's
+// Chaum-Pedersen proof does not verify against the published verification
+// key.
+var ErrInvalidShareProof = errors.New("threshold: decryption share failed verification")
+
+// KeyShare is one trustee's share of the Paillier decryption key d. It is
+// produced by GenerateThresholdKey and is private to the trustee that holds
+// it; PartialDecrypt is the only operation that should consume it.
+type KeyShare struct {
+	Index    int      `json:"index"` // 1-based trustee index, i.e. f(Index)
+	Share    *big.Int `json:"share"` // s_i = f(Index) mod N*m
+	T        int      `json:"t"`
+	N        int      `json:"n"`
+	N1       *big.Int `json:"n1"`
+	NSquared *big.Int `json:"n_squared"`
+	Delta    *big.Int `json:"delta"` // n!
+}
+
+// VerificationKey lets any party check a trustee's partial decryption
+// against its published per-trustee verification key v_i, without learning
+// the trustee's share.
+type VerificationKey struct {
+	T        int        `json:"t"`
+	N        int        `json:"n"`
+	N1       *big.Int   `json:"n1"`
+	NSquared *big.Int   `json:"n_squared"`
+	Delta    *big.Int   `json:"delta"`
+	V        *big.Int   `json:"v"`  // random square mod N^2
+	VIs      []*big.Int `json:"vi"` // VIs[i-1] = v^(Delta*s_i) mod N^2
+}
+
+// ChaumPedersenProof proves, in zero knowledge, that the same exponent was
+// used to compute two values relative to two different bases: that
+// log_{c4}(share^2) == log_{V}(v_i).
+type ChaumPedersenProof struct {
+	A1 *big.Int `json:"a1"`
+	A2 *big.Int `json:"a2"`
+	E  *big.Int `json:"e"`
+	Z  *big.Int `json:"z"`
+}
+
+// DecryptionShare is a trustee's partial decryption of a ciphertext, along
+// with a proof that it was computed correctly with respect to the trustee's
+// published verification key.
+type DecryptionShare struct {
+	Index int                 `json:"index"`
+	Share *big.Int            `json:"share"` // c_i = c^(2*Delta*s_i) mod N^2
+	Proof *ChaumPedersenProof `json:"proof"`
+	Delta *big.Int            `json:"delta"` // n! fixed at key generation, not len(shares)
+	T     int                 `json:"t"`     // quorum size required to combine, fixed at key generation
+}
+
+// GenerateThresholdKey generates a Paillier key of the given bit size and
+// splits its private exponent across n trustees such that any t of them can
+// combine their DecryptionShares to recover a plaintext, while fewer than t
+// learn nothing. It uses a safe-prime modulus N=pq, p=2p'+1, q=2q'+1, so
+// that m=p'q' is the order of the subgroup the secret exponent d lives in.
+func GenerateThresholdKey(random io.Reader, bits, t, n int) (*paillier.PublicKey, []*KeyShare, *VerificationKey, error) {
+	if t < 1 || t > n {
+		return nil, nil, nil, errors.New("threshold: require 1 <= t <= n")
+	}
+
+	p, pPrime, err := paillier.SafePrime(random, bits/2)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	q, qPrime, err := paillier.SafePrime(random, bits/2)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if pPrime.Cmp(qPrime) == 0 {
+		return nil, nil, nil, errors.New("threshold: p' and q' collided, retry key generation")
+	}
+	if p.BitLen() != q.BitLen() {
+		return nil, nil, nil, errors.New("threshold: p and q differ in bit length, retry key generation")
+	}
+
+	N := new(big.Int).Mul(p, q)
+	NSquared := new(big.Int).Mul(N, N)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	if new(big.Int).GCD(nil, nil, N, phi).Cmp(one) != 0 {
+		return nil, nil, nil, errors.New("threshold: gcd(N, phi(N)) != 1, retry key generation")
+	}
+	m := new(big.Int).Mul(pPrime, qPrime)
+	Nm := new(big.Int).Mul(N, m)
+
+	// d satisfies d == 0 mod m and d == 1 mod N, so that (1+N)^d == 1+dN
+	// reduces the exponent to a multiple of m, matching standard Paillier
+	// decryption while remaining Shamir-shareable over Z_{Nm}.
+	d, err := crtZero1(m, N)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	delta := factorial(n)
+
+	shares, err := shamirSplit(random, d, Nm, t, n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	v, err := randomSquare(random, NSquared)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	vIs := make([]*big.Int, n)
+	keyShares := make([]*KeyShare, n)
+	for i := 1; i <= n; i++ {
+		exp := new(big.Int).Mul(delta, shares[i-1])
+		vIs[i-1] = new(big.Int).Exp(v, exp, NSquared)
+		keyShares[i-1] = &KeyShare{
+			Index:    i,
+			Share:    shares[i-1],
+			T:        t,
+			N:        n,
+			N1:       N,
+			NSquared: NSquared,
+			Delta:    delta,
+		}
+	}
+
+	pub := &paillier.PublicKey{
+		N1:       N,
+		NSquared: NSquared,
+		G:        new(big.Int).Add(N, one),
+	}
+	vk := &VerificationKey{
+		T:        t,
+		N:        n,
+		N1:       N,
+		NSquared: NSquared,
+		Delta:    delta,
+		V:        v,
+		VIs:      vIs,
+	}
+
+	return pub, keyShares, vk, nil
+}
+
+// PartialDecrypt computes trustee share's partial decryption of c, together
+// with a Chaum-Pedersen proof that it was computed honestly with respect to
+// the trustee's verification key.
+func PartialDecrypt(share *KeyShare, vk *VerificationKey, c *big.Int, random io.Reader) (*DecryptionShare, error) {
+	c4 := new(big.Int).Exp(c, big.NewInt(4), share.NSquared)
+
+	exp := new(big.Int).Mul(two, new(big.Int).Mul(share.Delta, share.Share))
+	ci := new(big.Int).Exp(c, exp, share.NSquared)
+
+	proof, err := proveChaumPedersen(random, c4, vk.V, new(big.Int).Mul(share.Delta, share.Share), share.NSquared, new(big.Int).Exp(ci, two, share.NSquared), vk.VIs[share.Index-1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecryptionShare{
+		Index: share.Index,
+		Share: ci,
+		Proof: proof,
+		Delta: share.Delta,
+		T:     share.T,
+	}, nil
+}
+
+// VerifyShare checks ds's Chaum-Pedersen proof against the trustee's
+// published verification key, returning ErrInvalidShareProof if it fails.
+func VerifyShare(vk *VerificationKey, c *big.Int, ds *DecryptionShare) error {
+	if ds.Index < 1 || ds.Index > len(vk.VIs) {
+		return errors.New("threshold: decryption share index out of range")
+	}
+	c4 := new(big.Int).Exp(c, big.NewInt(4), vk.NSquared)
+	ciSquared := new(big.Int).Exp(ds.Share, two, vk.NSquared)
+	vi := vk.VIs[ds.Index-1]
+
+	if !verifyChaumPedersen(ds.Proof, c4, vk.V, ciSquared, vi, vk.NSquared) {
+		return ErrInvalidShareProof
+	}
+	return nil
+}
+
+// CombineShares Lagrange-interpolates t or more verified decryption shares
+// to recover c^(4*Delta^2*d) mod N^2, then applies the L-function and the
+// modular inverse of 4*Delta^2 mod N1 to recover the plaintext.
+func CombineShares(pub *paillier.PublicKey, c *big.Int, shares []*DecryptionShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	indices := make([]int, len(shares))
+	for i, s := range shares {
+		indices[i] = s.Index
+	}
+	if len(indices) != countDistinct(indices) {
+		return nil, errors.New("threshold: duplicate decryption share indices")
+	}
+
+	// T is fixed at key generation time and baked into every share; a quorum
+	// smaller than T must be rejected rather than silently Lagrange-interpolated
+	// into a bogus result.
+	t := shares[0].T
+	for _, s := range shares {
+		if s.T != t {
+			return nil, errors.New("threshold: decryption shares disagree on T")
+		}
+	}
+	if len(shares) < t {
+		return nil, ErrNotEnoughShares
+	}
+
+	// Delta=n! is fixed at key generation time and baked into every share;
+	// it must NOT be recomputed from however many shares happen to be
+	// combined here, or Lagrange interpolation is wrong whenever t < n.
+	delta := shares[0].Delta
+	for _, s := range shares {
+		if s.Delta.Cmp(delta) != 0 {
+			return nil, errors.New("threshold: decryption shares disagree on Delta")
+		}
+	}
+
+	combined := big.NewInt(1)
+	for _, s := range shares {
+		lambda := lagrangeCoefficient(s.Index, indices, delta)
+		// Each share is c_i = c^(2*Delta*s_i); raising it to 2*lambda_i,
+		// where Sigma lambda_i*s_i = Delta*d, makes the product
+		// c^(4*Delta*Delta*d), the value CombineShares documents above.
+		exp := new(big.Int).Mul(two, lambda)
+		var term *big.Int
+		if exp.Sign() < 0 {
+			inv := new(big.Int).ModInverse(s.Share, pub.NSquared)
+			if inv == nil {
+				return nil, errors.New("threshold: decryption share not invertible mod N^2")
+			}
+			term = new(big.Int).Exp(inv, new(big.Int).Neg(exp), pub.NSquared)
+		} else {
+			term = new(big.Int).Exp(s.Share, exp, pub.NSquared)
+		}
+		combined.Mul(combined, term)
+		combined.Mod(combined, pub.NSquared)
+	}
+
+	lu := new(big.Int).Div(new(big.Int).Sub(combined, one), pub.N1)
+
+	fourDeltaSquared := new(big.Int).Mul(big.NewInt(4), new(big.Int).Mul(delta, delta))
+	inv := new(big.Int).ModInverse(new(big.Int).Mod(fourDeltaSquared, pub.N1), pub.N1)
+	if inv == nil {
+		return nil, errors.New("threshold: 4*Delta^2 not invertible mod N1")
+	}
+
+	m := new(big.Int).Mod(new(big.Int).Mul(lu, inv), pub.N1)
+	return m.Bytes(), nil
+}
+
+func countDistinct(xs []int) int {
+	seen := make(map[int]struct{}, len(xs))
+	for _, x := range xs {
+		seen[x] = struct{}{}
+	}
+	return len(seen)
+}
+
+// lagrangeCoefficient returns Delta * l_i(0) for the interpolation set
+// indices, where l_i is the Lagrange basis polynomial for index i. Delta=n!
+// guarantees the result is an integer even though l_i(0) generally is not.
+func lagrangeCoefficient(i int, indices []int, delta *big.Int) *big.Int {
+	num := new(big.Int).Set(delta)
+	den := big.NewInt(1)
+	for _, j := range indices {
+		if j == i {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(-j)))
+		den.Mul(den, big.NewInt(int64(i-j)))
+	}
+	return new(big.Int).Div(num, den)
+}
+
+// crtZero1 returns d such that d == 0 mod m and d == 1 mod n, using
+// d = m * (m^-1 mod n) mod (m*n).
+func crtZero1(m, n *big.Int) (*big.Int, error) {
+	mInv := new(big.Int).ModInverse(m, n)
+	if mInv == nil {
+		return nil, errors.New("threshold: m and N are not coprime, retry key generation")
+	}
+	mn := new(big.Int).Mul(m, n)
+	d := new(big.Int).Mod(new(big.Int).Mul(m, mInv), mn)
+	return d, nil
+}
+
+// shamirSplit builds a random degree-(t-1) polynomial f over Z_mod with
+// f(0)=secret and returns [f(1), ..., f(n)] mod mod.
+func shamirSplit(random io.Reader, secret, mod *big.Int, t, n int) ([]*big.Int, error) {
+	coeffs := make([]*big.Int, t)
+	coeffs[0] = secret
+	for i := 1; i < t; i++ {
+		c, err := rand.Int(random, mod)
+		if err != nil {
+			return nil, err
+		}
+		coeffs[i] = c
+	}
+
+	shares := make([]*big.Int, n)
+	for i := 1; i <= n; i++ {
+		x := big.NewInt(int64(i))
+		acc := new(big.Int).Set(coeffs[t-1])
+		for k := t - 2; k >= 0; k-- {
+			acc.Mul(acc, x)
+			acc.Add(acc, coeffs[k])
+			acc.Mod(acc, mod)
+		}
+		shares[i-1] = acc
+	}
+	return shares, nil
+}
+
+// randomSquare returns a random non-trivial square in Z_mod*.
+func randomSquare(random io.Reader, mod *big.Int) (*big.Int, error) {
+	r, err := rand.Int(random, mod)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sign() == 0 {
+		r = one
+	}
+	return new(big.Int).Exp(r, two, mod), nil
+}
+
+func factorial(n int) *big.Int {
+	f := big.NewInt(1)
+	for i := int64(2); i <= int64(n); i++ {
+		f.Mul(f, big.NewInt(i))
+	}
+	return f
+}
+
+// proveChaumPedersen proves knowledge of exponent x such that valueB1 ==
+// base1^x mod mod and valueB2 == base2^x mod mod, using a Fiat-Shamir
+// transformed Schnorr-style proof of equal discrete logarithms.
+func proveChaumPedersen(random io.Reader, base1, base2, x, mod, valueB1, valueB2 *big.Int) (*ChaumPedersenProof, error) {
+	// omega is sampled from a range large enough to statistically hide x.
+	bound := new(big.Int).Lsh(mod, 128)
+	omega, err := rand.Int(random, bound)
+	if err != nil {
+		return nil, err
+	}
+
+	a1 := new(big.Int).Exp(base1, omega, mod)
+	a2 := new(big.Int).Exp(base2, omega, mod)
+
+	e := challengeHash(mod, base1, base2, valueB1, valueB2, a1, a2)
+	z := new(big.Int).Add(omega, new(big.Int).Mul(e, x))
+
+	return &ChaumPedersenProof{A1: a1, A2: a2, E: e, Z: z}, nil
+}
+
+func verifyChaumPedersen(proof *ChaumPedersenProof, base1, base2, valueB1, valueB2, mod *big.Int) bool {
+	e := challengeHash(mod, base1, base2, valueB1, valueB2, proof.A1, proof.A2)
+	if e.Cmp(proof.E) != 0 {
+		return false
+	}
+
+	lhs1 := new(big.Int).Exp(base1, proof.Z, mod)
+	rhs1 := new(big.Int).Mod(new(big.Int).Mul(proof.A1, new(big.Int).Exp(valueB1, e, mod)), mod)
+	if lhs1.Cmp(rhs1) != 0 {
+		return false
+	}
+
+	lhs2 := new(big.Int).Exp(base2, proof.Z, mod)
+	rhs2 := new(big.Int).Mod(new(big.Int).Mul(proof.A2, new(big.Int).Exp(valueB2, e, mod)), mod)
+	return lhs2.Cmp(rhs2) == 0
+}
+
+func challengeHash(values ...*big.Int) *big.Int {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write(v.Bytes())
+	}
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
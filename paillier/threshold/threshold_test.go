@@ -0,0 +1,178 @@
+package threshold
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/Microsoft-tele/PaillierVoteSystemRemoteRouter/paillier"
+)
+
+func TestThresholdDecryptRoundTrip(t *testing.T) {
+	const bits = 256
+	const tThreshold = 2
+	const n = 3
+
+	pub, shares, vk, err := GenerateThresholdKey(rand.Reader, bits, tThreshold, n)
+	if err != nil {
+		t.Fatalf("GenerateThresholdKey: %v", err)
+	}
+
+	plainText := big.NewInt(42)
+	cipherBytes, err := paillier.Encrypt(pub, plainText.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	c := new(big.Int).SetBytes(cipherBytes)
+
+	var decryptionShares []*DecryptionShare
+	for _, share := range shares[:tThreshold] {
+		ds, err := PartialDecrypt(share, vk, c, rand.Reader)
+		if err != nil {
+			t.Fatalf("PartialDecrypt(trustee %d): %v", share.Index, err)
+		}
+		if err := VerifyShare(vk, c, ds); err != nil {
+			t.Fatalf("VerifyShare(trustee %d): %v", share.Index, err)
+		}
+		decryptionShares = append(decryptionShares, ds)
+	}
+
+	recovered, err := CombineShares(pub, c, decryptionShares)
+	if err != nil {
+		t.Fatalf("CombineShares: %v", err)
+	}
+
+	got := new(big.Int).SetBytes(recovered)
+	if got.Cmp(plainText) != 0 {
+		t.Fatalf("CombineShares with t=%d of n=%d shares = %s, want %s", tThreshold, n, got, plainText)
+	}
+}
+
+func TestThresholdDecryptAllShares(t *testing.T) {
+	const bits = 256
+	const tThreshold = 3
+	const n = 3
+
+	pub, shares, vk, err := GenerateThresholdKey(rand.Reader, bits, tThreshold, n)
+	if err != nil {
+		t.Fatalf("GenerateThresholdKey: %v", err)
+	}
+
+	plainText := big.NewInt(43)
+	cipherBytes, err := paillier.Encrypt(pub, plainText.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	c := new(big.Int).SetBytes(cipherBytes)
+
+	var decryptionShares []*DecryptionShare
+	for _, share := range shares {
+		ds, err := PartialDecrypt(share, vk, c, rand.Reader)
+		if err != nil {
+			t.Fatalf("PartialDecrypt(trustee %d): %v", share.Index, err)
+		}
+		decryptionShares = append(decryptionShares, ds)
+	}
+
+	recovered, err := CombineShares(pub, c, decryptionShares)
+	if err != nil {
+		t.Fatalf("CombineShares: %v", err)
+	}
+
+	got := new(big.Int).SetBytes(recovered)
+	if got.Cmp(plainText) != 0 {
+		t.Fatalf("CombineShares with all n=%d shares = %s, want %s", n, got, plainText)
+	}
+}
+
+func TestThresholdDecryptBelowThreshold(t *testing.T) {
+	const bits = 256
+	const tThreshold = 3
+	const n = 5
+
+	pub, shares, vk, err := GenerateThresholdKey(rand.Reader, bits, tThreshold, n)
+	if err != nil {
+		t.Fatalf("GenerateThresholdKey: %v", err)
+	}
+
+	plainText := big.NewInt(7)
+	cipherBytes, err := paillier.Encrypt(pub, plainText.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	c := new(big.Int).SetBytes(cipherBytes)
+
+	var decryptionShares []*DecryptionShare
+	for _, share := range shares[:tThreshold-1] {
+		ds, err := PartialDecrypt(share, vk, c, rand.Reader)
+		if err != nil {
+			t.Fatalf("PartialDecrypt(trustee %d): %v", share.Index, err)
+		}
+		if err := VerifyShare(vk, c, ds); err != nil {
+			t.Fatalf("VerifyShare(trustee %d): %v", share.Index, err)
+		}
+		decryptionShares = append(decryptionShares, ds)
+	}
+
+	if _, err := CombineShares(pub, c, decryptionShares); err != ErrNotEnoughShares {
+		t.Fatalf("CombineShares with %d of t=%d shares = %v, want ErrNotEnoughShares", tThreshold-1, tThreshold, err)
+	}
+}
+
+func TestThresholdDecryptDuplicateIndices(t *testing.T) {
+	const bits = 256
+	const tThreshold = 2
+	const n = 3
+
+	pub, shares, vk, err := GenerateThresholdKey(rand.Reader, bits, tThreshold, n)
+	if err != nil {
+		t.Fatalf("GenerateThresholdKey: %v", err)
+	}
+
+	plainText := big.NewInt(11)
+	cipherBytes, err := paillier.Encrypt(pub, plainText.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	c := new(big.Int).SetBytes(cipherBytes)
+
+	ds, err := PartialDecrypt(shares[0], vk, c, rand.Reader)
+	if err != nil {
+		t.Fatalf("PartialDecrypt(trustee %d): %v", shares[0].Index, err)
+	}
+	if err := VerifyShare(vk, c, ds); err != nil {
+		t.Fatalf("VerifyShare(trustee %d): %v", shares[0].Index, err)
+	}
+
+	if _, err := CombineShares(pub, c, []*DecryptionShare{ds, ds}); err == nil {
+		t.Fatal("CombineShares accepted duplicate decryption share indices")
+	}
+}
+
+func TestThresholdVerifyShareRejectsTamperedShare(t *testing.T) {
+	const bits = 256
+	const tThreshold = 2
+	const n = 3
+
+	pub, shares, vk, err := GenerateThresholdKey(rand.Reader, bits, tThreshold, n)
+	if err != nil {
+		t.Fatalf("GenerateThresholdKey: %v", err)
+	}
+
+	plainText := big.NewInt(13)
+	cipherBytes, err := paillier.Encrypt(pub, plainText.Bytes())
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	c := new(big.Int).SetBytes(cipherBytes)
+
+	ds, err := PartialDecrypt(shares[0], vk, c, rand.Reader)
+	if err != nil {
+		t.Fatalf("PartialDecrypt(trustee %d): %v", shares[0].Index, err)
+	}
+	ds.Share = new(big.Int).Add(ds.Share, big.NewInt(1))
+
+	if err := VerifyShare(vk, c, ds); err != ErrInvalidShareProof {
+		t.Fatalf("VerifyShare(tampered share) = %v, want ErrInvalidShareProof", err)
+	}
+}
@@ -0,0 +1,234 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidBitProof is returned by VerifyBitProof when a ciphertext's
+// disjunctive proof does not verify.
+var ErrInvalidBitProof = errors.New("paillier: bit proof failed verification")
+
+// ErrInvalidSumProof is returned by VerifySum when a ballot's linear sum
+// proof does not verify.
+var ErrInvalidSumProof = errors.New("paillier: sum proof failed verification")
+
+// BitProof is a Fiat-Shamir transformed disjunctive Sigma proof that a
+// ciphertext encrypts 0 or 1, without revealing which.
+type BitProof struct {
+	A0 *big.Int `json:"a0"`
+	A1 *big.Int `json:"a1"`
+	E0 *big.Int `json:"e0"`
+	E1 *big.Int `json:"e1"`
+	Z0 *big.Int `json:"z0"`
+	Z1 *big.Int `json:"z1"`
+}
+
+// EncryptBit encrypts a single bit (0 or 1) under pub using nonce, and
+// produces a BitProof that the resulting ciphertext encrypts a value in
+// {0,1} without revealing which. It returns an error if bit is not 0 or 1.
+func EncryptBit(pub *PublicKey, bit int, nonce *big.Int) (ciphertext []byte, proof *BitProof, err error) {
+	if bit != 0 && bit != 1 {
+		return nil, nil, errors.New("paillier: bit must be 0 or 1")
+	}
+
+	c, err := EncryptWithNonce(pub, nonce, big.NewInt(int64(bit)).Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proof, err = proveBit(pub, bit, nonce, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.Bytes(), proof, nil
+}
+
+// VerifyBitProof checks that ciphertext encrypts 0 or 1 under pub, per
+// proof, returning ErrInvalidBitProof if the proof does not verify.
+func VerifyBitProof(pub *PublicKey, ciphertext []byte, proof *BitProof) error {
+	c := new(big.Int).SetBytes(ciphertext)
+
+	e := bitChallenge(pub, c, proof.A0, proof.A1)
+	if e.Cmp(new(big.Int).Mod(new(big.Int).Add(proof.E0, proof.E1), pub.N1)) != 0 {
+		return ErrInvalidBitProof
+	}
+
+	if !checkBitBranch(pub, c, 0, proof.A0, proof.E0, proof.Z0) {
+		return ErrInvalidBitProof
+	}
+	if !checkBitBranch(pub, c, 1, proof.A1, proof.E1, proof.Z1) {
+		return ErrInvalidBitProof
+	}
+	return nil
+}
+
+// proveBit builds the disjunctive proof: the branch matching the real bit
+// is proven honestly, the other branch is simulated from a randomly chosen
+// challenge and response.
+func proveBit(pub *PublicKey, bit int, r, c *big.Int) (*BitProof, error) {
+	falseBit := 1 - bit
+
+	eFalse, err := rand.Int(rand.Reader, pub.N1)
+	if err != nil {
+		return nil, err
+	}
+	zFalse, err := rand.Int(rand.Reader, pub.N1)
+	if err != nil {
+		return nil, err
+	}
+	aFalse := simulateBitCommitment(pub, c, falseBit, eFalse, zFalse)
+
+	omega, err := rand.Int(rand.Reader, pub.N1)
+	if err != nil {
+		return nil, err
+	}
+	aReal := new(big.Int).Exp(omega, pub.N1, pub.NSquared)
+
+	var a0, a1 *big.Int
+	if bit == 0 {
+		a0, a1 = aReal, aFalse
+	} else {
+		a0, a1 = aFalse, aReal
+	}
+
+	e := bitChallenge(pub, c, a0, a1)
+	eReal := new(big.Int).Mod(new(big.Int).Sub(e, eFalse), pub.N1)
+	zReal := new(big.Int).Mod(new(big.Int).Mul(omega, new(big.Int).Exp(r, eReal, pub.N1)), pub.N1)
+
+	proof := &BitProof{A0: a0, A1: a1}
+	if bit == 0 {
+		proof.E0, proof.Z0 = eReal, zReal
+		proof.E1, proof.Z1 = eFalse, zFalse
+	} else {
+		proof.E0, proof.Z0 = eFalse, zFalse
+		proof.E1, proof.Z1 = eReal, zReal
+	}
+	return proof, nil
+}
+
+// simulateBitCommitment computes a_b = z^N * (c*g^-b)^-e mod N^2, the
+// commitment that makes branch b's verification equation hold for a
+// chosen challenge e and response z.
+func simulateBitCommitment(pub *PublicKey, c *big.Int, b int, e, z *big.Int) *big.Int {
+	target := bitTarget(pub, c, b)
+	targetToE := new(big.Int).Exp(target, e, pub.NSquared)
+	targetToEInv := new(big.Int).ModInverse(targetToE, pub.NSquared)
+	zToN := new(big.Int).Exp(z, pub.N1, pub.NSquared)
+	return new(big.Int).Mod(new(big.Int).Mul(zToN, targetToEInv), pub.NSquared)
+}
+
+func checkBitBranch(pub *PublicKey, c *big.Int, b int, a, e, z *big.Int) bool {
+	target := bitTarget(pub, c, b)
+	lhs := new(big.Int).Exp(z, pub.N1, pub.NSquared)
+	rhs := new(big.Int).Mod(new(big.Int).Mul(a, new(big.Int).Exp(target, e, pub.NSquared)), pub.NSquared)
+	return lhs.Cmp(rhs) == 0
+}
+
+// bitTarget returns c * g^-b mod N^2, the value whose N-th root is the
+// randomness used to encrypt b, when c does in fact encrypt b.
+func bitTarget(pub *PublicKey, c *big.Int, b int) *big.Int {
+	gToB := new(big.Int).Exp(pub.G, big.NewInt(int64(b)), pub.NSquared)
+	gToBInv := new(big.Int).ModInverse(gToB, pub.NSquared)
+	return new(big.Int).Mod(new(big.Int).Mul(c, gToBInv), pub.NSquared)
+}
+
+func bitChallenge(pub *PublicKey, c, a0, a1 *big.Int) *big.Int {
+	h := sha256.New()
+	h.Write(pub.N1.Bytes())
+	h.Write(c.Bytes())
+	h.Write(a0.Bytes())
+	h.Write(a1.Bytes())
+	return new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), pub.N1)
+}
+
+// SumProof proves, without decrypting any ciphertext, that a set of
+// ciphertexts encrypting 0/1 values sum to a known total k. It is a
+// Schnorr-style proof of knowledge of an N-th root of the combined
+// ciphertext divided by g^k.
+type SumProof struct {
+	A *big.Int `json:"a"`
+	E *big.Int `json:"e"`
+	Z *big.Int `json:"z"`
+}
+
+// ProveSum proves that the plaintexts behind ciphertexts (encrypted with
+// the given nonces) sum to k. This is the invariant a ballot must satisfy:
+// exactly k of its per-candidate bits are 1.
+func ProveSum(pub *PublicKey, ciphertexts [][]byte, nonces []*big.Int, k int) (*SumProof, error) {
+	if len(ciphertexts) != len(nonces) {
+		return nil, errors.New("paillier: ciphertexts and nonces length mismatch")
+	}
+
+	combinedNonce := big.NewInt(1)
+	for _, r := range nonces {
+		combinedNonce.Mul(combinedNonce, r)
+		combinedNonce.Mod(combinedNonce, pub.N1)
+	}
+
+	target, err := sumTarget(pub, ciphertexts, k)
+	if err != nil {
+		return nil, err
+	}
+
+	omega, err := rand.Int(rand.Reader, pub.N1)
+	if err != nil {
+		return nil, err
+	}
+	a := new(big.Int).Exp(omega, pub.N1, pub.NSquared)
+	e := sumChallenge(pub, target, a)
+	z := new(big.Int).Mod(new(big.Int).Mul(omega, new(big.Int).Exp(combinedNonce, e, pub.N1)), pub.N1)
+
+	return &SumProof{A: a, E: e, Z: z}, nil
+}
+
+// VerifySum checks a SumProof produced by ProveSum against the same
+// ciphertexts and expected total k.
+func VerifySum(pub *PublicKey, ciphertexts [][]byte, k int, proof *SumProof) error {
+	target, err := sumTarget(pub, ciphertexts, k)
+	if err != nil {
+		return err
+	}
+
+	e := sumChallenge(pub, target, proof.A)
+	if e.Cmp(proof.E) != 0 {
+		return ErrInvalidSumProof
+	}
+
+	lhs := new(big.Int).Exp(proof.Z, pub.N1, pub.NSquared)
+	rhs := new(big.Int).Mod(new(big.Int).Mul(proof.A, new(big.Int).Exp(target, proof.E, pub.NSquared)), pub.NSquared)
+	if lhs.Cmp(rhs) != 0 {
+		return ErrInvalidSumProof
+	}
+	return nil
+}
+
+// sumTarget combines the ciphertexts homomorphically and divides out g^k,
+// leaving a value whose N-th root is the combined randomness, iff the
+// plaintexts really do sum to k.
+func sumTarget(pub *PublicKey, ciphertexts [][]byte, k int) (*big.Int, error) {
+	combined := big.NewInt(1)
+	for _, ct := range ciphertexts {
+		c := new(big.Int).SetBytes(ct)
+		combined.Mul(combined, c)
+		combined.Mod(combined, pub.NSquared)
+	}
+
+	gToK := new(big.Int).Exp(pub.G, big.NewInt(int64(k)), pub.NSquared)
+	gToKInv := new(big.Int).ModInverse(gToK, pub.NSquared)
+	if gToKInv == nil {
+		return nil, errors.New("paillier: g^k not invertible mod N^2")
+	}
+	return new(big.Int).Mod(new(big.Int).Mul(combined, gToKInv), pub.NSquared), nil
+}
+
+func sumChallenge(pub *PublicKey, target, a *big.Int) *big.Int {
+	h := sha256.New()
+	h.Write(pub.N1.Bytes())
+	h.Write(target.Bytes())
+	h.Write(a.Bytes())
+	return new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), pub.N1)
+}
@@ -0,0 +1,134 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestBitProofRoundTrip(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	for _, bit := range []int{0, 1} {
+		nonce, err := rand.Int(rand.Reader, priv.N1)
+		if err != nil {
+			t.Fatalf("rand.Int: %v", err)
+		}
+
+		ciphertext, proof, err := EncryptBit(&priv.PublicKey, bit, nonce)
+		if err != nil {
+			t.Fatalf("EncryptBit(%d): %v", bit, err)
+		}
+		if err := VerifyBitProof(&priv.PublicKey, ciphertext, proof); err != nil {
+			t.Fatalf("VerifyBitProof(%d): %v", bit, err)
+		}
+
+		plain, err := Decrypt(priv, ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt(%d): %v", bit, err)
+		}
+		got := new(big.Int).SetBytes(plain)
+		if got.Int64() != int64(bit) {
+			t.Fatalf("decrypted bit = %d, want %d", got.Int64(), bit)
+		}
+	}
+
+	if _, _, err := EncryptBit(&priv.PublicKey, 2, big.NewInt(1)); err == nil {
+		t.Fatal("EncryptBit accepted a non-bit value")
+	}
+}
+
+func TestVerifyBitProofRejectsTamperedProof(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	nonce, err := rand.Int(rand.Reader, priv.N1)
+	if err != nil {
+		t.Fatalf("rand.Int: %v", err)
+	}
+	ciphertext, proof, err := EncryptBit(&priv.PublicKey, 1, nonce)
+	if err != nil {
+		t.Fatalf("EncryptBit: %v", err)
+	}
+
+	proof.Z0 = new(big.Int).Add(proof.Z0, big.NewInt(1))
+	if err := VerifyBitProof(&priv.PublicKey, ciphertext, proof); err == nil {
+		t.Fatal("VerifyBitProof accepted a proof with a tampered Z0")
+	}
+}
+
+func TestVerifyBitProofRejectsWrongPlaintext(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	nonce, err := rand.Int(rand.Reader, priv.N1)
+	if err != nil {
+		t.Fatalf("rand.Int: %v", err)
+	}
+
+	// c actually encrypts 2, not 0 or 1; proveBit is asked to dishonestly
+	// claim it encrypts 0 so VerifyBitProof must reject the result.
+	c, err := EncryptWithNonce(&priv.PublicKey, nonce, big.NewInt(2).Bytes())
+	if err != nil {
+		t.Fatalf("EncryptWithNonce: %v", err)
+	}
+	proof, err := proveBit(&priv.PublicKey, 0, nonce, c)
+	if err != nil {
+		t.Fatalf("proveBit: %v", err)
+	}
+
+	if err := VerifyBitProof(&priv.PublicKey, c.Bytes(), proof); err == nil {
+		t.Fatal("VerifyBitProof accepted a bit=0 proof for a ciphertext encrypting 2")
+	}
+}
+
+func TestSumProofRoundTrip(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader, 256)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	votes := []int{0, 1, 0, 1, 0}
+	k := 0
+	var ciphertexts [][]byte
+	var nonces []*big.Int
+	for _, v := range votes {
+		k += v
+		nonce, err := rand.Int(rand.Reader, priv.N1)
+		if err != nil {
+			t.Fatalf("rand.Int: %v", err)
+		}
+		c, proof, err := EncryptBit(&priv.PublicKey, v, nonce)
+		if err != nil {
+			t.Fatalf("EncryptBit: %v", err)
+		}
+		if err := VerifyBitProof(&priv.PublicKey, c, proof); err != nil {
+			t.Fatalf("VerifyBitProof: %v", err)
+		}
+		ciphertexts = append(ciphertexts, c)
+		nonces = append(nonces, nonce)
+	}
+
+	proof, err := ProveSum(&priv.PublicKey, ciphertexts, nonces, k)
+	if err != nil {
+		t.Fatalf("ProveSum: %v", err)
+	}
+	if err := VerifySum(&priv.PublicKey, ciphertexts, k, proof); err != nil {
+		t.Fatalf("VerifySum: %v", err)
+	}
+	if err := VerifySum(&priv.PublicKey, ciphertexts, k+1, proof); err == nil {
+		t.Fatal("VerifySum accepted a ballot with the wrong total")
+	}
+
+	tampered := &SumProof{A: proof.A, E: proof.E, Z: new(big.Int).Add(proof.Z, big.NewInt(1))}
+	if err := VerifySum(&priv.PublicKey, ciphertexts, k, tampered); err == nil {
+		t.Fatal("VerifySum accepted a proof with a tampered Z")
+	}
+}